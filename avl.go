@@ -23,10 +23,19 @@ type Ordered interface {
 // A Node holds an Ordered element of the AVL tree in
 // the Val field.
 type Node struct {
-	Val Ordered
-	c   [2]*Node
-	p   *Node
-	b   int8
+	Val  Ordered
+	c    [2]*Node
+	p    *Node
+	b    int8
+	size int32
+}
+
+// size returns the number of nodes in n's subtree, or 0 if n is nil.
+func size(n *Node) int32 {
+	if n == nil {
+		return 0
+	}
+	return n.size
 }
 
 // Size returns the number of elements stored in the tree.
@@ -71,7 +80,7 @@ func (t *Tree) insert(val Ordered, p *Node, qp **Node) bool {
 	q := *qp
 	if q == nil {
 		t.size++
-		*qp = &Node{Val: val, p: p}
+		*qp = &Node{Val: val, p: p, size: 1}
 		return true
 	}
 
@@ -83,6 +92,7 @@ func (t *Tree) insert(val Ordered, p *Node, qp **Node) bool {
 
 	a := (c + 1) / 2
 	fix := t.insert(val, q, &q.c[a])
+	q.size = 1 + size(q.c[0]) + size(q.c[1])
 	if fix {
 		return insertFix(c, qp)
 	}
@@ -119,6 +129,7 @@ func (t *Tree) del(val Ordered, qp **Node) bool {
 			return true
 		}
 		fix := delmin(&q.c[1], &q.Val)
+		q.size = 1 + size(q.c[0]) + size(q.c[1])
 		if fix {
 			return delFix(-1, qp)
 		}
@@ -126,6 +137,7 @@ func (t *Tree) del(val Ordered, qp **Node) bool {
 	}
 	a := (c + 1) / 2
 	fix := t.del(val, &q.c[a])
+	q.size = 1 + size(q.c[0]) + size(q.c[1])
 	if fix {
 		return delFix(-c, qp)
 	}
@@ -143,6 +155,7 @@ func delmin(qp **Node, min *Ordered) bool {
 		return true
 	}
 	fix := delmin(&q.c[0], min)
+	q.size = 1 + size(q.c[0]) + size(q.c[1])
 	if fix {
 		return delFix(1, qp)
 	}
@@ -257,10 +270,72 @@ func rotate(c int8, s *Node) *Node {
 	r.c[a^1] = s
 	r.p = s.p
 	s.p = r
+	s.size = 1 + size(s.c[0]) + size(s.c[1])
+	r.size = 1 + size(r.c[0]) + size(r.c[1])
 	dbgLog.Printf("rotate: exit %p:%v\n", r, r)
 	return r
 }
 
+// Select returns the element at index k in the tree's inorder
+// sequence (so k == 0 is the minimum), or nil if k is out of range.
+func (t *Tree) Select(k int) *Node {
+	if t == nil {
+		return nil
+	}
+	n := t.root
+	for n != nil {
+		left := int(size(n.c[0]))
+		switch {
+		case k < left:
+			n = n.c[0]
+		case k == left:
+			return n
+		default:
+			k -= left + 1
+			n = n.c[1]
+		}
+	}
+	return nil
+}
+
+// Rank returns the number of elements in the tree that are strictly
+// less than val.
+//
+// Val's Less implementation must be able to handle comparisons to
+// elements stored in this tree.
+func (t *Tree) Rank(val Ordered) int {
+	if t == nil {
+		return 0
+	}
+	n := t.root
+	rank := 0
+	for n != nil {
+		switch cmp(val, n.Val) {
+		case -1:
+			n = n.c[0]
+		case 0:
+			return rank + int(size(n.c[0]))
+		case 1:
+			rank += int(size(n.c[0])) + 1
+			n = n.c[1]
+		}
+	}
+	return rank
+}
+
+// Index returns n's index in its tree's inorder sequence, i.e. the
+// number of elements less than n.Val.
+func (n *Node) Index() int {
+	idx := int(size(n.c[0]))
+	for p := n.p; p != nil; p = p.p {
+		if p.c[1] == n {
+			idx += int(size(p.c[0])) + 1
+		}
+		n = p
+	}
+	return idx
+}
+
 // Min returns the minimum element of the AVL tree
 // or nil if the tree is empty.
 func (t *Tree) Min() *Node {
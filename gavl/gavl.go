@@ -0,0 +1,317 @@
+// Package gavl implements a generic AVL balanced binary tree, keyed by a
+// user-supplied comparator, mirroring the direction taken by containers
+// such as containers.Ordered in the btrfs-progs-ng containers package.
+//
+// Tree compares keys with a typed func(K, K) int stored in the tree, in
+// place of the avl package's interface-dispatched Less calls. This
+// package doesn't replace avl.Tree or oldavl.Tree: Node here carries no
+// order-statistics size, so it can't back PathHint or Select/Rank, which
+// read and maintain that field directly on avl.Node. Making
+// avl.Tree or oldavl.Tree a wrapper over Tree[K, V] would mean adding
+// that state to this package too, at which point it stops being a
+// lighter-weight core and just duplicates avl.Node under a different
+// name. The three trees stay separate implementations kept for
+// backwards compatibility, not wrappers around a shared one.
+package gavl
+
+// Tree holds key/value pairs ordered by cmp.
+type Tree[K any, V any] struct {
+	root *Node[K, V]
+	size int
+	cmp  func(K, K) int
+}
+
+// A Node holds a key/value pair of the Tree.
+type Node[K any, V any] struct {
+	Key   K
+	Value V
+	c     [2]*Node[K, V]
+	p     *Node[K, V]
+	b     int8
+}
+
+// NewTree returns an empty Tree ordered by cmp. cmp(a, b) must return a
+// negative number if a < b, a positive number if a > b, and 0 if a and b
+// are equal.
+func NewTree[K any, V any](cmp func(K, K) int) *Tree[K, V] {
+	return &Tree[K, V]{cmp: cmp}
+}
+
+// Size returns the number of elements stored in the tree.
+func (t *Tree[K, V]) Size() int {
+	if t == nil {
+		return 0
+	}
+	return t.size
+}
+
+// Lookup looks up key and returns its value if it is found.
+func (t *Tree[K, V]) Lookup(key K) (value V, ok bool) {
+	if t == nil {
+		return
+	}
+	n := t.root
+	for n != nil {
+		switch sign(t.cmp(key, n.Key)) {
+		case -1:
+			n = n.c[0]
+		case 0:
+			return n.Value, true
+		case 1:
+			n = n.c[1]
+		}
+	}
+	return
+}
+
+// Insert inserts key and val into the tree. If key is already present,
+// its old value is replaced and returned.
+func (t *Tree[K, V]) Insert(key K, val V) (old V, replaced bool) {
+	t.insert(key, val, nil, &t.root, &old, &replaced)
+	return
+}
+
+func (t *Tree[K, V]) insert(key K, val V, p *Node[K, V], qp **Node[K, V], old *V, replaced *bool) bool {
+	q := *qp
+	if q == nil {
+		t.size++
+		*qp = &Node[K, V]{Key: key, Value: val, p: p}
+		return true
+	}
+
+	c := sign(t.cmp(key, q.Key))
+	if c == 0 {
+		*old, *replaced = q.Value, true
+		q.Value = val
+		return false
+	}
+
+	a := (c + 1) / 2
+	fix := t.insert(key, val, q, &q.c[a], old, replaced)
+	if fix {
+		return insertFix(c, qp)
+	}
+	return false
+}
+
+// Delete removes the element matching key from the tree, if present, and
+// returns its value.
+func (t *Tree[K, V]) Delete(key K) (old V, found bool) {
+	if t == nil {
+		return
+	}
+	t.del(key, &t.root, &old, &found)
+	return
+}
+
+func (t *Tree[K, V]) del(key K, qp **Node[K, V], old *V, found *bool) bool {
+	q := *qp
+	if q == nil {
+		return false
+	}
+
+	c := sign(t.cmp(key, q.Key))
+	if c == 0 {
+		*old, *found = q.Value, true
+		t.size--
+		if q.c[1] == nil {
+			if q.c[0] != nil {
+				q.c[0].p = q.p
+			}
+			*qp = q.c[0]
+			return true
+		}
+		fix := delmin(&q.c[1], &q.Key, &q.Value)
+		if fix {
+			return delFix(-1, qp)
+		}
+		return false
+	}
+
+	a := (c + 1) / 2
+	fix := t.del(key, &q.c[a], old, found)
+	if fix {
+		return delFix(-c, qp)
+	}
+	return false
+}
+
+func delmin[K any, V any](qp **Node[K, V], minKey *K, minVal *V) bool {
+	q := *qp
+	if q.c[0] == nil {
+		*minKey, *minVal = q.Key, q.Value
+		if q.c[1] != nil {
+			q.c[1].p = q.p
+		}
+		*qp = q.c[1]
+		return true
+	}
+	fix := delmin(&q.c[0], minKey, minVal)
+	if fix {
+		return delFix(1, qp)
+	}
+	return false
+}
+
+func sign(c int) int8 {
+	switch {
+	case c < 0:
+		return -1
+	case c > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func insertFix[K any, V any](c int8, t **Node[K, V]) bool {
+	s := *t
+	if s.b == 0 {
+		s.b = c
+		return true
+	}
+
+	if s.b == -c {
+		s.b = 0
+		return false
+	}
+
+	if s.c[(c+1)/2].b == c {
+		s = singlerot(c, s)
+	} else {
+		s = doublerot(c, s)
+	}
+	*t = s
+	return false
+}
+
+func delFix[K any, V any](c int8, t **Node[K, V]) bool {
+	s := *t
+	if s.b == 0 {
+		s.b = c
+		return false
+	}
+
+	if s.b == -c {
+		s.b = 0
+		return true
+	}
+
+	a := (c + 1) / 2
+	if s.c[a].b == 0 {
+		s = rotate(c, s)
+		s.b = -c
+		*t = s
+		return false
+	}
+
+	if s.c[a].b == c {
+		s = singlerot(c, s)
+	} else {
+		s = doublerot(c, s)
+	}
+	*t = s
+	return true
+}
+
+func singlerot[K any, V any](c int8, s *Node[K, V]) *Node[K, V] {
+	s.b = 0
+	s = rotate(c, s)
+	s.b = 0
+	return s
+}
+
+func doublerot[K any, V any](c int8, s *Node[K, V]) *Node[K, V] {
+	a := (c + 1) / 2
+	r := s.c[a]
+	s.c[a] = rotate(-c, s.c[a])
+	p := rotate(c, s)
+	if r.p != p || s.p != p {
+		panic("doublerot: bad parents")
+	}
+
+	switch {
+	default:
+		s.b = 0
+		r.b = 0
+	case p.b == c:
+		s.b = -c
+		r.b = 0
+	case p.b == -c:
+		s.b = 0
+		r.b = c
+	}
+
+	p.b = 0
+	return p
+}
+
+func rotate[K any, V any](c int8, s *Node[K, V]) *Node[K, V] {
+	a := (c + 1) / 2
+	r := s.c[a]
+	s.c[a] = r.c[a^1]
+	if s.c[a] != nil {
+		s.c[a].p = s
+	}
+	r.c[a^1] = s
+	r.p = s.p
+	s.p = r
+	return r
+}
+
+// Min returns the element with the smallest key, or nil if the tree is
+// empty.
+func (t *Tree[K, V]) Min() *Node[K, V] {
+	return t.bottom(0)
+}
+
+// Max returns the element with the largest key, or nil if the tree is
+// empty.
+func (t *Tree[K, V]) Max() *Node[K, V] {
+	return t.bottom(1)
+}
+
+func (t *Tree[K, V]) bottom(d int) *Node[K, V] {
+	if t == nil {
+		return nil
+	}
+	n := t.root
+	if n == nil {
+		return nil
+	}
+	for c := n.c[d]; c != nil; c = n.c[d] {
+		n = c
+	}
+	return n
+}
+
+// Prev returns the previous element in key order.
+func (n *Node[K, V]) Prev() *Node[K, V] {
+	return n.walk1(0)
+}
+
+// Next returns the next element in key order.
+func (n *Node[K, V]) Next() *Node[K, V] {
+	return n.walk1(1)
+}
+
+func (n *Node[K, V]) walk1(a int) *Node[K, V] {
+	if n == nil {
+		return nil
+	}
+
+	if n.c[a] != nil {
+		n = n.c[a]
+		for n.c[a^1] != nil {
+			n = n.c[a^1]
+		}
+		return n
+	}
+
+	p := n.p
+	for p != nil && p.c[a] == n {
+		n = p
+		p = p.p
+	}
+	return p
+}
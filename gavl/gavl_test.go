@@ -0,0 +1,125 @@
+package gavl
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+const (
+	randMax = 2000
+	nNodes  = 1000
+	nDels   = 300
+)
+
+var rng *rand.Rand
+
+func TestMain(m *testing.M) {
+	rng = rand.New(rand.NewSource(time.Now().UTC().UnixNano()))
+	m.Run()
+}
+
+func newRandTree(n, randMax int) *OrderedTree[int, string] {
+	tree := NewOrderedTree[int, string]()
+	for i := 0; i < n; i++ {
+		v := rng.Intn(randMax)
+		tree.Insert(v, "")
+	}
+	return tree
+}
+
+func TestInsertOrdered(t *testing.T) {
+	tree := newRandTree(nNodes, randMax)
+	n := tree.Min()
+	for next := n.Next(); next != nil; next = n.Next() {
+		if next.Key <= n.Key {
+			t.Errorf("tree not ordered: %d ≮ %d", n.Key, next.Key)
+		}
+		n = next
+	}
+}
+
+func TestInsertBalanced(t *testing.T) {
+	tree := newRandTree(nNodes, randMax)
+	for n := tree.Min(); n != nil; n = n.Next() {
+		if !n.checkBalance() {
+			t.Errorf("tree not balanced at key %d", n.Key)
+		}
+	}
+}
+
+func (n *Node[K, V]) checkBalance() bool {
+	b := depth(n.c[1]) - depth(n.c[0])
+	return int8(b) == n.b
+}
+
+func depth[K any, V any](n *Node[K, V]) int {
+	if n == nil {
+		return 0
+	}
+	ld, rd := depth(n.c[0]), depth(n.c[1])
+	if ld >= rd {
+		return ld + 1
+	}
+	return rd + 1
+}
+
+func TestInsertReplace(t *testing.T) {
+	tree := NewOrderedTree[int, string]()
+	tree.Insert(1, "first")
+	old, replaced := tree.Insert(1, "second")
+	if !replaced || old != "first" {
+		t.Errorf("Insert(1, \"second\") = %q, %v, want %q, true", old, replaced, "first")
+	}
+	if v, _ := tree.Lookup(1); v != "second" {
+		t.Errorf("Lookup(1) = %q, want %q", v, "second")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tree := NewOrderedTree[int, string]()
+	vals := make(map[int]bool)
+	for i := 0; i < nNodes; i++ {
+		v := rng.Intn(randMax)
+		tree.Insert(v, "")
+		vals[v] = true
+	}
+	for i := 0; i < randMax; i++ {
+		_, inTree := tree.Lookup(i)
+		if inTree != vals[i] {
+			t.Errorf("Lookup(%d) = %v, want %v", i, inTree, vals[i])
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tree := newRandTree(nNodes, randMax)
+	size := tree.Size()
+	for i := 0; i < nDels; i++ {
+		if _, found := tree.Delete(rng.Intn(randMax)); found {
+			size--
+		}
+	}
+	if tree.Size() != size {
+		t.Errorf("Size() = %d, want %d", tree.Size(), size)
+	}
+	for n := tree.Min(); n != nil; n = n.Next() {
+		if !n.checkBalance() {
+			t.Errorf("tree not balanced at key %d after deletions", n.Key)
+		}
+	}
+}
+
+func TestCustomComparator(t *testing.T) {
+	type rec struct {
+		id   int
+		name string
+	}
+	tree := NewTree[int, rec](func(a, b int) int { return a - b })
+	tree.Insert(1, rec{1, "a"})
+	tree.Insert(2, rec{2, "b"})
+	v, ok := tree.Lookup(2)
+	if !ok || v.name != "b" {
+		t.Errorf("Lookup(2) = %+v, %v, want {2 b}, true", v, ok)
+	}
+}
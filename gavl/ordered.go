@@ -0,0 +1,15 @@
+package gavl
+
+import "cmp"
+
+// OrderedTree is a Tree whose keys are compared with cmp.Compare, for any
+// K that satisfies cmp.Ordered. It avoids having to write a comparator
+// by hand for the common case of plain ordered keys.
+type OrderedTree[K cmp.Ordered, V any] struct {
+	*Tree[K, V]
+}
+
+// NewOrderedTree returns an empty OrderedTree.
+func NewOrderedTree[K cmp.Ordered, V any]() *OrderedTree[K, V] {
+	return &OrderedTree[K, V]{Tree: NewTree[K, V](cmp.Compare[K])}
+}
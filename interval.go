@@ -0,0 +1,300 @@
+package avl
+
+// Interval is implemented by values stored in an IntervalTree. Low and
+// High define the closed interval [Low(), High()]. Equal reports whether
+// two intervals represent the same element, and is used by Delete to
+// disambiguate intervals that share a Low and High.
+type Interval interface {
+	Low() Ordered
+	High() Ordered
+	Equal(Interval) bool
+}
+
+// An inode holds one Interval of an IntervalTree, augmented with max,
+// the largest High of any interval in the subtree rooted at the node.
+type inode struct {
+	iv  Interval
+	max Ordered
+	c   [2]*inode
+	p   *inode
+	b   int8
+}
+
+// IntervalTree holds a set of intervals, which may overlap, and supports
+// stabbing and overlap queries. It is balanced the same way as Tree,
+// with each node additionally tracking the maximum High in its subtree.
+type IntervalTree struct {
+	root *inode
+	size int
+}
+
+// Size returns the number of intervals stored in the tree.
+func (t *IntervalTree) Size() int {
+	return t.size
+}
+
+// ivcmp orders intervals by Low, then by High.
+func ivcmp(a, b Interval) int8 {
+	if c := cmp(a.Low(), b.Low()); c != 0 {
+		return c
+	}
+	return cmp(a.High(), b.High())
+}
+
+func maxOrdered(a, b Ordered) Ordered {
+	if cmp(a, b) == 1 {
+		return a
+	}
+	return b
+}
+
+// fixMax recomputes n.max from n.iv.High and the max of n's children. It
+// must be called after any change to n's interval or children.
+func (n *inode) fixMax() {
+	m := n.iv.High()
+	if n.c[0] != nil {
+		m = maxOrdered(m, n.c[0].max)
+	}
+	if n.c[1] != nil {
+		m = maxOrdered(m, n.c[1].max)
+	}
+	n.max = m
+}
+
+// Insert inserts iv into the tree. If an interval with the same Low and
+// High is already present, it is replaced.
+func (t *IntervalTree) Insert(iv Interval) {
+	t.insert(iv, nil, &t.root)
+}
+
+func (t *IntervalTree) insert(iv Interval, p *inode, qp **inode) bool {
+	q := *qp
+	if q == nil {
+		t.size++
+		*qp = &inode{iv: iv, max: iv.High(), p: p}
+		return true
+	}
+
+	c := ivcmp(iv, q.iv)
+	if c == 0 {
+		q.iv = iv
+		q.fixMax()
+		return false
+	}
+
+	a := (c + 1) / 2
+	fix := t.insert(iv, q, &q.c[a])
+	q.fixMax()
+	if fix {
+		return insertFixI(c, qp)
+	}
+	return false
+}
+
+// Delete removes iv from the tree, if present.
+func (t *IntervalTree) Delete(iv Interval) {
+	if t == nil {
+		return
+	}
+	t.del(iv, &t.root)
+}
+
+func (t *IntervalTree) del(iv Interval, qp **inode) bool {
+	q := *qp
+	if q == nil {
+		return false
+	}
+
+	c := ivcmp(iv, q.iv)
+	if c == 0 {
+		if !iv.Equal(q.iv) {
+			return false
+		}
+		t.size--
+		if q.c[1] == nil {
+			if q.c[0] != nil {
+				q.c[0].p = q.p
+			}
+			*qp = q.c[0]
+			return true
+		}
+		fix := delminI(&q.c[1], &q.iv)
+		q.fixMax()
+		if fix {
+			return delFixI(-1, qp)
+		}
+		return false
+	}
+
+	a := (c + 1) / 2
+	fix := t.del(iv, &q.c[a])
+	q.fixMax()
+	if fix {
+		return delFixI(-c, qp)
+	}
+	return false
+}
+
+func delminI(qp **inode, min *Interval) bool {
+	q := *qp
+	if q.c[0] == nil {
+		*min = q.iv
+		if q.c[1] != nil {
+			q.c[1].p = q.p
+		}
+		*qp = q.c[1]
+		return true
+	}
+	fix := delminI(&q.c[0], min)
+	q.fixMax()
+	if fix {
+		return delFixI(1, qp)
+	}
+	return false
+}
+
+func insertFixI(c int8, t **inode) bool {
+	s := *t
+	if s.b == 0 {
+		s.b = c
+		return true
+	}
+
+	if s.b == -c {
+		s.b = 0
+		return false
+	}
+
+	if s.c[(c+1)/2].b == c {
+		s = singlerotI(c, s)
+	} else {
+		s = doublerotI(c, s)
+	}
+	*t = s
+	return false
+}
+
+func delFixI(c int8, t **inode) bool {
+	s := *t
+	if s.b == 0 {
+		s.b = c
+		return false
+	}
+
+	if s.b == -c {
+		s.b = 0
+		return true
+	}
+
+	a := (c + 1) / 2
+	if s.c[a].b == 0 {
+		s = rotateI(c, s)
+		s.b = -c
+		*t = s
+		return false
+	}
+
+	if s.c[a].b == c {
+		s = singlerotI(c, s)
+	} else {
+		s = doublerotI(c, s)
+	}
+	*t = s
+	return true
+}
+
+func singlerotI(c int8, s *inode) *inode {
+	s.b = 0
+	s = rotateI(c, s)
+	s.b = 0
+	return s
+}
+
+func doublerotI(c int8, s *inode) *inode {
+	a := (c + 1) / 2
+	r := s.c[a]
+	s.c[a] = rotateI(-c, s.c[a])
+	p := rotateI(c, s)
+	if r.p != p || s.p != p {
+		panic("doublerotI: bad parents")
+	}
+
+	switch {
+	default:
+		s.b = 0
+		r.b = 0
+	case p.b == c:
+		s.b = -c
+		r.b = 0
+	case p.b == -c:
+		s.b = 0
+		r.b = c
+	}
+
+	p.b = 0
+	return p
+}
+
+func rotateI(c int8, s *inode) *inode {
+	a := (c + 1) / 2
+	r := s.c[a]
+	s.c[a] = r.c[a^1]
+	if s.c[a] != nil {
+		s.c[a].p = s
+	}
+	r.c[a^1] = s
+	r.p = s.p
+	s.p = r
+	s.fixMax()
+	r.fixMax()
+	return r
+}
+
+// Search returns every interval in t that contains point.
+func (t *IntervalTree) Search(point Ordered) []Interval {
+	if t == nil {
+		return nil
+	}
+	return t.SearchOverlap(pointInterval{point})
+}
+
+// SearchOverlap returns every interval in t that overlaps iv, i.e. every
+// stored interval [Low, High] with Low ≤ iv.High() and iv.Low() ≤ High.
+func (t *IntervalTree) SearchOverlap(iv Interval) []Interval {
+	if t == nil {
+		return nil
+	}
+	var out []Interval
+	t.root.searchOverlap(iv, &out)
+	return out
+}
+
+func (n *inode) searchOverlap(iv Interval, out *[]Interval) {
+	if n == nil {
+		return
+	}
+
+	if cmp(n.max, iv.Low()) != -1 {
+		n.c[0].searchOverlap(iv, out)
+	}
+
+	if cmp(n.iv.Low(), iv.High()) != 1 && cmp(iv.Low(), n.iv.High()) != 1 {
+		*out = append(*out, n.iv)
+	}
+
+	if cmp(iv.High(), n.iv.Low()) != -1 {
+		n.c[1].searchOverlap(iv, out)
+	}
+}
+
+// pointInterval adapts a single Ordered value to the Interval interface,
+// so Search can be implemented in terms of SearchOverlap.
+type pointInterval struct {
+	val Ordered
+}
+
+func (p pointInterval) Low() Ordered  { return p.val }
+func (p pointInterval) High() Ordered { return p.val }
+func (p pointInterval) Equal(other Interval) bool {
+	return cmp(p.val, other.Low()) == 0 && cmp(p.val, other.High()) == 0
+}
@@ -0,0 +1,100 @@
+package avl
+
+import "testing"
+
+type IntInterval struct {
+	lo, hi Int
+}
+
+func (iv IntInterval) Low() Ordered  { return iv.lo }
+func (iv IntInterval) High() Ordered { return iv.hi }
+
+func (iv IntInterval) Equal(other Interval) bool {
+	o := other.(IntInterval)
+	return iv.lo == o.lo && iv.hi == o.hi
+}
+
+func newRandIntervalTree(n, randMax, maxSpan int) (tree *IntervalTree, ivs []IntInterval) {
+	tree = new(IntervalTree)
+	for i := 0; i < n; i++ {
+		lo := rng.Intn(randMax)
+		iv := IntInterval{Int(lo), Int(lo + rng.Intn(maxSpan))}
+		tree.Insert(iv)
+		ivs = append(ivs, iv)
+	}
+	return
+}
+
+func TestIntervalInsertSize(t *testing.T) {
+	tree, ivs := newRandIntervalTree(nNodes, randMax, 20)
+	if tree.Size() > len(ivs) {
+		t.Errorf("tree has more intervals than were inserted: %d > %d", tree.Size(), len(ivs))
+	}
+}
+
+func TestIntervalMaxInvariant(t *testing.T) {
+	tree, _ := newRandIntervalTree(nNodes, randMax, 20)
+	tree.root.checkMax(t)
+}
+
+func TestIntervalDeleteMaxInvariant(t *testing.T) {
+	tree, ivs := newRandIntervalTree(nNodes, randMax, 20)
+	for i := 0; i < nDels && i < len(ivs); i++ {
+		tree.Delete(ivs[i])
+	}
+	tree.root.checkMax(t)
+}
+
+func (n *inode) checkMax(t *testing.T) Ordered {
+	if n == nil {
+		return nil
+	}
+
+	want := n.iv.High()
+	if left := n.c[0].checkMax(t); left != nil && cmp(left, want) == 1 {
+		want = left
+	}
+	if right := n.c[1].checkMax(t); right != nil && cmp(right, want) == 1 {
+		want = right
+	}
+	if cmp(n.max, want) != 0 {
+		t.Errorf("node max %v, want %v", n.max, want)
+	}
+	return n.max
+}
+
+func TestIntervalSearch(t *testing.T) {
+	tree, ivs := newRandIntervalTree(nNodes, randMax, 20)
+	for point := 0; point < randMax; point += 37 {
+		got := tree.Search(Int(point))
+		gotSet := map[IntInterval]bool{}
+		for _, iv := range got {
+			gotSet[iv.(IntInterval)] = true
+		}
+		for _, iv := range ivs {
+			want := iv.lo <= Int(point) && Int(point) <= iv.hi
+			if want != gotSet[iv] {
+				t.Errorf("Search(%d): interval %v present %v, want %v", point, iv, gotSet[iv], want)
+			}
+		}
+	}
+}
+
+func TestIntervalSearchOverlap(t *testing.T) {
+	tree, ivs := newRandIntervalTree(nNodes, randMax, 20)
+	for i := 0; i < 20; i++ {
+		lo := rng.Intn(randMax)
+		query := IntInterval{Int(lo), Int(lo + rng.Intn(20))}
+		got := tree.SearchOverlap(query)
+		gotSet := map[IntInterval]bool{}
+		for _, iv := range got {
+			gotSet[iv.(IntInterval)] = true
+		}
+		for _, iv := range ivs {
+			want := iv.lo <= query.hi && query.lo <= iv.hi
+			if want != gotSet[iv] {
+				t.Errorf("SearchOverlap(%v): interval %v present %v, want %v", query, iv, gotSet[iv], want)
+			}
+		}
+	}
+}
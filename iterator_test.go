@@ -0,0 +1,153 @@
+package avl
+
+import (
+	"sort"
+	"testing"
+)
+
+func sortedVals(vals map[Int]bool) []int {
+	var sorted []int
+	for v := range vals {
+		sorted = append(sorted, int(v))
+	}
+	sort.Ints(sorted)
+	return sorted
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestIteratorAscend(t *testing.T) {
+	tree, vals := newRandIntTreeAndMap(nNodes, randMax)
+	sorted := sortedVals(vals)
+
+	it := tree.Iterator()
+	i := 0
+	for it.Next() {
+		if got := int(it.Value().(Int)); got != sorted[i] {
+			t.Fatalf("element %d = %d, want %d", i, got, sorted[i])
+		}
+		i++
+	}
+	if i != len(sorted) {
+		t.Fatalf("walked %d elements, want %d", i, len(sorted))
+	}
+}
+
+func TestIteratorDescend(t *testing.T) {
+	tree, vals := newRandIntTreeAndMap(nNodes, randMax)
+	sorted := sortedVals(vals)
+
+	it := tree.Iterator()
+	i := len(sorted) - 1
+	for it.Prev() {
+		if got := int(it.Value().(Int)); got != sorted[i] {
+			t.Fatalf("element %d = %d, want %d", i, got, sorted[i])
+		}
+		i--
+	}
+	if i != -1 {
+		t.Fatalf("walked to index %d, want -1", i)
+	}
+}
+
+func TestIteratorAt(t *testing.T) {
+	tree, vals := newRandIntTreeAndMap(nNodes, randMax)
+	sorted := sortedVals(vals)
+
+	for probe := 0; probe < randMax; probe += 7 {
+		it := tree.IteratorAt(Int(probe))
+		want := sort.SearchInts(sorted, probe)
+		if want == len(sorted) {
+			if it.Value() != nil {
+				t.Fatalf("IteratorAt(%d) = %v, want none", probe, it.Value())
+			}
+			continue
+		}
+		if got := int(it.Value().(Int)); got != sorted[want] {
+			t.Fatalf("IteratorAt(%d) = %d, want %d", probe, got, sorted[want])
+		}
+	}
+}
+
+func TestRange(t *testing.T) {
+	tree, vals := newRandIntTreeAndMap(nNodes, randMax)
+	sorted := sortedVals(vals)
+
+	for i := 0; i < 20; i++ {
+		lo := rng.Intn(randMax)
+		hi := lo + rng.Intn(100)
+		var want []int
+		for _, v := range sorted {
+			if v >= lo && v < hi {
+				want = append(want, v)
+			}
+		}
+
+		var got []int
+		it := tree.Range(Int(lo), Int(hi))
+		for it.Value() != nil {
+			got = append(got, int(it.Value().(Int)))
+			if !it.Next() {
+				break
+			}
+		}
+		if !equalInts(got, want) {
+			t.Fatalf("Range(%d,%d) = %v, want %v", lo, hi, got, want)
+		}
+	}
+}
+
+func TestReverseRange(t *testing.T) {
+	tree, vals := newRandIntTreeAndMap(nNodes, randMax)
+	sorted := sortedVals(vals)
+
+	for i := 0; i < 20; i++ {
+		hi := rng.Intn(randMax)
+		lo := hi - rng.Intn(100)
+		var want []int
+		for j := len(sorted) - 1; j >= 0; j-- {
+			v := sorted[j]
+			if v <= hi && v > lo {
+				want = append(want, v)
+			}
+		}
+
+		var got []int
+		it := tree.ReverseRange(Int(hi), Int(lo))
+		for it.Value() != nil {
+			got = append(got, int(it.Value().(Int)))
+			if !it.Prev() {
+				break
+			}
+		}
+		if !equalInts(got, want) {
+			t.Fatalf("ReverseRange(%d,%d) = %v, want %v", hi, lo, got, want)
+		}
+	}
+}
+
+// TestRangeBoundsLandingElement covers the case where the first element
+// Seek/reverseSeek lands on already violates the range's bound: a Range or
+// ReverseRange whose only candidate element falls outside [lo, hi) or
+// (lo, hi] must report no elements at all, not expose that element once.
+func TestRangeBoundsLandingElement(t *testing.T) {
+	tree := new(Tree)
+	tree.Insert(Int(169))
+
+	if v := tree.Range(Int(91), Int(94)).Value(); v != nil {
+		t.Errorf("Range(91,94) landed on %v, want none", v)
+	}
+	if v := tree.ReverseRange(Int(250), Int(200)).Value(); v != nil {
+		t.Errorf("ReverseRange(250,200) landed on %v, want none", v)
+	}
+}
@@ -0,0 +1,253 @@
+package oldavl
+
+// Iterator positions within the ordered sequence of an Iterator. posNone
+// is the zero value: a freshly created iterator that has never been
+// advanced in either direction.
+const (
+	posNone = iota
+	posBefore
+	posAt
+	posAfter
+)
+
+// Iterator walks the elements of a Tree in ascending or descending order
+// using an explicit ancestor stack instead of following parent pointers.
+//
+// The zero Iterator is not usable; create one with Tree.Iterator,
+// Tree.IteratorAt, Tree.Range, or Tree.ReverseRange.
+type Iterator struct {
+	t     *Tree
+	stack []*Node
+	pos   int
+
+	hasHi bool
+	hi    interface{}
+	hasLo bool
+	lo    interface{}
+}
+
+// Iterator returns an Iterator positioned before the first element of t.
+// Call Next to advance to the first element.
+func (t *Tree) Iterator() *Iterator {
+	return &Iterator{t: t}
+}
+
+// IteratorAt returns an Iterator positioned at the first element ≥ val.
+func (t *Tree) IteratorAt(val interface{}) *Iterator {
+	it := t.Iterator()
+	it.Seek(val)
+	return it
+}
+
+// Range returns an Iterator over [lo, hi), positioned at the first element
+// ≥ lo. Next returns false once it would advance past the last element <
+// hi.
+func (t *Tree) Range(lo, hi interface{}) *Iterator {
+	it := t.IteratorAt(lo)
+	it.hasHi, it.hi = true, hi
+	if it.pos == posAt && it.t.cmp(it.Value(), hi) >= 0 {
+		it.pos = posAfter
+	}
+	return it
+}
+
+// ReverseRange returns an Iterator over (lo, hi], positioned at the last
+// element ≤ hi and descending. Prev returns false once it would retreat
+// past the first element > lo.
+func (t *Tree) ReverseRange(hi, lo interface{}) *Iterator {
+	it := t.Iterator()
+	it.reverseSeek(hi)
+	it.hasLo, it.lo = true, lo
+	if it.pos == posAt && it.t.cmp(it.Value(), lo) <= 0 {
+		it.pos = posBefore
+	}
+	return it
+}
+
+// AscendGreaterOrEqual calls iter for every element of t that is ≥ pivot,
+// in ascending order, until iter returns false.
+func (t *Tree) AscendGreaterOrEqual(pivot interface{}, iter func(interface{}) bool) {
+	it := t.IteratorAt(pivot)
+	for it.pos == posAt {
+		if !iter(it.Value()) {
+			return
+		}
+		if !it.Next() {
+			return
+		}
+	}
+}
+
+// DescendLessOrEqual calls iter for every element of t that is ≤ pivot, in
+// descending order, until iter returns false.
+func (t *Tree) DescendLessOrEqual(pivot interface{}, iter func(interface{}) bool) {
+	it := t.Iterator()
+	it.reverseSeek(pivot)
+	for it.pos == posAt {
+		if !iter(it.Value()) {
+			return
+		}
+		if !it.Prev() {
+			return
+		}
+	}
+}
+
+// Seek repositions it at the first element ≥ val, in O(log n) by
+// descending from the root once, and reports whether such an element
+// exists.
+func (it *Iterator) Seek(val interface{}) bool {
+	it.stack = it.stack[:0]
+	top := 0
+	n := it.t.root
+	for n != nil {
+		it.stack = append(it.stack, n)
+		if it.t.cmp(val, n.Val) > 0 {
+			n = n.c[1]
+			continue
+		}
+		top = len(it.stack)
+		n = n.c[0]
+	}
+	it.stack = it.stack[:top]
+	if len(it.stack) == 0 {
+		it.pos = posAfter
+		return false
+	}
+	it.pos = posAt
+	return true
+}
+
+// reverseSeek repositions it at the last element ≤ val.
+func (it *Iterator) reverseSeek(val interface{}) bool {
+	it.stack = it.stack[:0]
+	top := 0
+	n := it.t.root
+	for n != nil {
+		it.stack = append(it.stack, n)
+		if it.t.cmp(val, n.Val) < 0 {
+			n = n.c[0]
+			continue
+		}
+		top = len(it.stack)
+		n = n.c[1]
+	}
+	it.stack = it.stack[:top]
+	if len(it.stack) == 0 {
+		it.pos = posBefore
+		return false
+	}
+	it.pos = posAt
+	return true
+}
+
+// Value returns the element at the iterator's current position, or nil
+// if it is not positioned at an element.
+func (it *Iterator) Value() interface{} {
+	if it.pos != posAt {
+		return nil
+	}
+	return it.stack[len(it.stack)-1].Val
+}
+
+// Node returns the node at the iterator's current position, or nil if it
+// is not positioned at an element.
+func (it *Iterator) Node() *Node {
+	if it.pos != posAt {
+		return nil
+	}
+	return it.stack[len(it.stack)-1]
+}
+
+// Next advances it to the next larger element and reports whether one
+// was found.
+func (it *Iterator) Next() bool {
+	if !it.advance(1) {
+		return false
+	}
+	if it.hasHi && it.t.cmp(it.Value(), it.hi) >= 0 {
+		it.pos = posAfter
+		return false
+	}
+	return true
+}
+
+// Prev advances it to the next smaller element and reports whether one
+// was found.
+func (it *Iterator) Prev() bool {
+	if !it.advance(0) {
+		return false
+	}
+	if it.hasLo && it.t.cmp(it.Value(), it.lo) <= 0 {
+		it.pos = posBefore
+		return false
+	}
+	return true
+}
+
+// advance moves it one step in direction a (1 ascending, 0 descending)
+// using its ancestor stack in place of parent pointers.
+func (it *Iterator) advance(a int) bool {
+	switch it.pos {
+	case posNone:
+		it.spine(it.t.root, a^1)
+	case posBefore:
+		if a == 0 {
+			return false
+		}
+		it.spine(it.t.root, 0)
+	case posAfter:
+		if a == 1 {
+			return false
+		}
+		it.spine(it.t.root, 1)
+	default:
+		it.step(a)
+	}
+	if len(it.stack) == 0 {
+		it.pos = posBefore
+		if a == 1 {
+			it.pos = posAfter
+		}
+		return false
+	}
+	it.pos = posAt
+	return true
+}
+
+// spine pushes the chain of nodes reached by repeatedly descending via
+// c[a] from n, leaving the last one (the extreme element in that
+// direction) on top of the stack.
+func (it *Iterator) spine(n *Node, a int) {
+	it.stack = it.stack[:0]
+	for n != nil {
+		it.stack = append(it.stack, n)
+		n = n.c[a]
+	}
+}
+
+// step moves the top of the stack to its in-order successor (a == 1) or
+// predecessor (a == 0).
+func (it *Iterator) step(a int) {
+	n := it.stack[len(it.stack)-1]
+	if n.c[a] != nil {
+		n = n.c[a]
+		for n != nil {
+			it.stack = append(it.stack, n)
+			n = n.c[a^1]
+		}
+		return
+	}
+
+	for len(it.stack) > 0 {
+		it.stack = it.stack[:len(it.stack)-1]
+		if len(it.stack) == 0 {
+			return
+		}
+		p := it.stack[len(it.stack)-1]
+		if p.c[a] != n {
+			return
+		}
+		n = p
+	}
+}
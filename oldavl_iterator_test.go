@@ -0,0 +1,182 @@
+package oldavl
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+const (
+	randMax = 2000
+	nNodes  = 1000
+)
+
+var rng *rand.Rand
+
+func TestMain(m *testing.M) {
+	rng = rand.New(rand.NewSource(time.Now().UTC().UnixNano()))
+	m.Run()
+}
+
+func intCmp(a, b interface{}) int {
+	return a.(int) - b.(int)
+}
+
+func newRandIntTreeAndMap(n, randMax int) (tree *Tree, vals map[int]bool) {
+	tree = NewTree(intCmp)
+	vals = make(map[int]bool)
+	for i := 0; i < n; i++ {
+		v := rng.Intn(randMax)
+		tree.Insert(v)
+		vals[v] = true
+	}
+	return
+}
+
+func sortedVals(vals map[int]bool) []int {
+	var sorted []int
+	for v := range vals {
+		sorted = append(sorted, v)
+	}
+	sort.Ints(sorted)
+	return sorted
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestIteratorAscend(t *testing.T) {
+	tree, vals := newRandIntTreeAndMap(nNodes, randMax)
+	sorted := sortedVals(vals)
+
+	it := tree.Iterator()
+	i := 0
+	for it.Next() {
+		if got := it.Value().(int); got != sorted[i] {
+			t.Fatalf("element %d = %d, want %d", i, got, sorted[i])
+		}
+		i++
+	}
+	if i != len(sorted) {
+		t.Fatalf("walked %d elements, want %d", i, len(sorted))
+	}
+}
+
+func TestIteratorDescend(t *testing.T) {
+	tree, vals := newRandIntTreeAndMap(nNodes, randMax)
+	sorted := sortedVals(vals)
+
+	it := tree.Iterator()
+	i := len(sorted) - 1
+	for it.Prev() {
+		if got := it.Value().(int); got != sorted[i] {
+			t.Fatalf("element %d = %d, want %d", i, got, sorted[i])
+		}
+		i--
+	}
+	if i != -1 {
+		t.Fatalf("walked to index %d, want -1", i)
+	}
+}
+
+func TestIteratorAt(t *testing.T) {
+	tree, vals := newRandIntTreeAndMap(nNodes, randMax)
+	sorted := sortedVals(vals)
+
+	for probe := 0; probe < randMax; probe += 7 {
+		it := tree.IteratorAt(probe)
+		want := sort.SearchInts(sorted, probe)
+		if want == len(sorted) {
+			if it.Value() != nil {
+				t.Fatalf("IteratorAt(%d) = %v, want none", probe, it.Value())
+			}
+			continue
+		}
+		if got := it.Value().(int); got != sorted[want] {
+			t.Fatalf("IteratorAt(%d) = %d, want %d", probe, got, sorted[want])
+		}
+	}
+}
+
+func TestRange(t *testing.T) {
+	tree, vals := newRandIntTreeAndMap(nNodes, randMax)
+	sorted := sortedVals(vals)
+
+	for i := 0; i < 20; i++ {
+		lo := rng.Intn(randMax)
+		hi := lo + rng.Intn(100)
+		var want []int
+		for _, v := range sorted {
+			if v >= lo && v < hi {
+				want = append(want, v)
+			}
+		}
+
+		var got []int
+		it := tree.Range(lo, hi)
+		for it.Value() != nil {
+			got = append(got, it.Value().(int))
+			if !it.Next() {
+				break
+			}
+		}
+		if !equalInts(got, want) {
+			t.Fatalf("Range(%d,%d) = %v, want %v", lo, hi, got, want)
+		}
+	}
+}
+
+func TestReverseRange(t *testing.T) {
+	tree, vals := newRandIntTreeAndMap(nNodes, randMax)
+	sorted := sortedVals(vals)
+
+	for i := 0; i < 20; i++ {
+		hi := rng.Intn(randMax)
+		lo := hi - rng.Intn(100)
+		var want []int
+		for j := len(sorted) - 1; j >= 0; j-- {
+			v := sorted[j]
+			if v <= hi && v > lo {
+				want = append(want, v)
+			}
+		}
+
+		var got []int
+		it := tree.ReverseRange(hi, lo)
+		for it.Value() != nil {
+			got = append(got, it.Value().(int))
+			if !it.Prev() {
+				break
+			}
+		}
+		if !equalInts(got, want) {
+			t.Fatalf("ReverseRange(%d,%d) = %v, want %v", hi, lo, got, want)
+		}
+	}
+}
+
+// TestRangeBoundsLandingElement covers the case where the first element
+// Seek/reverseSeek lands on already violates the range's bound: a Range or
+// ReverseRange whose only candidate element falls outside [lo, hi) or
+// (lo, hi] must report no elements at all, not expose that element once.
+func TestRangeBoundsLandingElement(t *testing.T) {
+	tree := NewTree(intCmp)
+	tree.Insert(169)
+
+	if v := tree.Range(91, 94).Value(); v != nil {
+		t.Errorf("Range(91,94) landed on %v, want none", v)
+	}
+	if v := tree.ReverseRange(250, 200).Value(); v != nil {
+		t.Errorf("ReverseRange(250,200) landed on %v, want none", v)
+	}
+}
@@ -0,0 +1,86 @@
+package avl
+
+import (
+	"sort"
+	"testing"
+)
+
+func checkSizes(t *testing.T, n *Node) {
+	if n == nil {
+		return
+	}
+	want := 1 + size(n.c[0]) + size(n.c[1])
+	if n.size != want {
+		t.Errorf("node %v size %d, want %d", n.Val, n.size, want)
+	}
+	checkSizes(t, n.c[0])
+	checkSizes(t, n.c[1])
+}
+
+func TestInsertSizes(t *testing.T) {
+	tree := newRandIntTree(nNodes, randMax)
+	checkSizes(t, tree.root)
+}
+
+func TestDeleteSizes(t *testing.T) {
+	tree := newRandIntTree(nNodes, randMax)
+	tree.deleteSome(nDels)
+	checkSizes(t, tree.root)
+}
+
+func TestSelect(t *testing.T) {
+	tree, vals := newRandIntTreeAndMap(nNodes, randMax)
+	var sorted []int
+	for v := range vals {
+		sorted = append(sorted, int(v))
+	}
+	sort.Ints(sorted)
+	for k, want := range sorted {
+		n := tree.Select(k)
+		if n == nil || int(n.Val.(Int)) != want {
+			t.Fatalf("Select(%d) = %v, want %d", k, n, want)
+		}
+	}
+	if n := tree.Select(-1); n != nil {
+		t.Errorf("Select(-1) = %v, want nil", n)
+	}
+	if n := tree.Select(len(sorted)); n != nil {
+		t.Errorf("Select(%d) = %v, want nil", len(sorted), n)
+	}
+}
+
+func TestRank(t *testing.T) {
+	tree, vals := newRandIntTreeAndMap(nNodes, randMax)
+	var sorted []int
+	for v := range vals {
+		sorted = append(sorted, int(v))
+	}
+	sort.Ints(sorted)
+	for i := 0; i < randMax; i++ {
+		want := sort.SearchInts(sorted, i)
+		if got := tree.Rank(Int(i)); got != want {
+			t.Errorf("Rank(%d) = %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	tree := newRandIntTree(nNodes, randMax)
+	i := 0
+	for n := tree.Min(); n != nil; n = n.Next() {
+		if got := n.Index(); got != i {
+			t.Errorf("Index() at position %d = %d, want %d", i, got, i)
+		}
+		i++
+	}
+}
+
+func TestSelectIndexRoundTrip(t *testing.T) {
+	tree := newRandIntTree(nNodes, randMax)
+	for k := 0; k < tree.Size(); k++ {
+		n := tree.Select(k)
+		if got := n.Index(); got != k {
+			t.Errorf("Select(%d).Index() = %d, want %d", k, got, k)
+		}
+	}
+}
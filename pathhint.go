@@ -0,0 +1,311 @@
+package avl
+
+// PathHint records the sequence of child directions taken by a previous
+// descent of a Tree. A later LookupHint, InsertHint, or DeleteHint for a
+// nearby value can use it to relocate close to where that descent left
+// off, instead of redescending from the root. Passing a zero PathHint is
+// always safe: it simply means there's nothing to reuse yet.
+//
+// A PathHint is tied to a single Tree; don't share one between trees.
+type PathHint struct {
+	dir [32]uint8
+	len int8
+}
+
+// set records dir as the direction taken at level i, if i is within the
+// hint's capacity. Beyond that capacity the hint simply stops tracking
+// depth, which only costs a redescent from the deepest tracked level.
+func (h *PathHint) set(i int8, dir uint8) {
+	if int(i) < len(h.dir) {
+		h.dir[i] = dir
+	}
+}
+
+// follow descends from root along h's recorded directions using only
+// pointer dereferences, no comparisons, stopping early if the path runs
+// off the tree. It returns the node reached (nil if the tree is empty or
+// the path ran off it) and the depth reached.
+func (h *PathHint) follow(root *Node) (*Node, int8) {
+	n := root
+	var i int8
+	for i < h.len && int(i) < len(h.dir) && n != nil {
+		n = n.c[h.dir[i]]
+		i++
+	}
+	return n, i
+}
+
+// slotOf returns the address of the pointer that holds n: either &t.root,
+// or the appropriate child slot of n's parent.
+func slotOf(t *Tree, n *Node) **Node {
+	if n.p == nil {
+		return &t.root
+	}
+	if n.p.c[0] == n {
+		return &n.p.c[0]
+	}
+	return &n.p.c[1]
+}
+
+// ascend climbs from g, a node found at depth d that doesn't match val,
+// towards the root, comparing val against each ancestor in turn, until
+// val is bracketed between a lesser and a greater ancestor (or matches
+// one exactly, or the root is reached). Once val is bracketed, the node
+// below the more recently found bound is guaranteed to hold val in its
+// subtree, so an ordinary comparison-driven descent from there is
+// correct without climbing all the way to the root.
+func ascend(g *Node, d int8, val Ordered) (*Node, int8) {
+	cur, depth := g, d
+	var lo, hi *Node
+	for {
+		p := cur.p
+		if p == nil {
+			return cur, depth
+		}
+		switch cmp(val, p.Val) {
+		case 0:
+			return p, depth - 1
+		case -1:
+			if hi == nil {
+				hi = p
+			}
+		case 1:
+			if lo == nil {
+				lo = p
+			}
+		}
+		if lo != nil && hi != nil {
+			return cur, depth
+		}
+		cur, depth = p, depth-1
+	}
+}
+
+// LookupHint behaves like Lookup, but uses hint to relocate near val
+// instead of always descending from the root, and updates hint for
+// later calls. It's most effective for sequential scans or repeated
+// lookups of nearby values; its worst case is no worse than Lookup's.
+func (t *Tree) LookupHint(val Ordered, hint *PathHint) (match Ordered, ok bool) {
+	if t == nil {
+		return
+	}
+
+	g, d := hint.follow(t.root)
+	if g == nil {
+		return t.lookupFrom(t.root, val, hint, 0)
+	}
+	if cmp(val, g.Val) == 0 {
+		hint.len = d
+		return g.Val, true
+	}
+
+	r, rd := ascend(g, d, val)
+	return t.lookupFrom(r, val, hint, rd)
+}
+
+// lookupFrom performs a normal comparison-driven descent from n, which
+// is at depth d, recording directions into hint from d on.
+func (t *Tree) lookupFrom(n *Node, val Ordered, hint *PathHint, d int8) (match Ordered, ok bool) {
+	for n != nil {
+		switch cmp(val, n.Val) {
+		case -1:
+			hint.set(d, 0)
+			n = n.c[0]
+		case 0:
+			hint.len = d
+			return n.Val, true
+		case 1:
+			hint.set(d, 1)
+			n = n.c[1]
+		}
+		d++
+	}
+	hint.len = d
+	return
+}
+
+// InsertHint behaves like Insert, but uses hint to relocate near val
+// instead of always descending from the root, and updates hint for
+// later calls.
+func (t *Tree) InsertHint(val Ordered, hint *PathHint) {
+	g, d := hint.follow(t.root)
+	if g == nil {
+		t.insert(val, nil, &t.root)
+		hint.len = 0
+		return
+	}
+	if cmp(val, g.Val) == 0 {
+		g.Val = val
+		hint.len = d
+		return
+	}
+
+	r, rd := ascend(g, d, val)
+	t.insertFrom(r, val, hint, rd)
+}
+
+// insertFrom performs a normal comparison-driven descent from r, which is
+// at depth d, to insert val, recording directions into hint from d on and
+// keeping every ancestor's size correct, then rebalances upward from the
+// new node's parent.
+// It's the resume-from-r counterpart of t.insert, which always starts
+// from the root.
+func (t *Tree) insertFrom(r *Node, val Ordered, hint *PathHint, d int8) {
+	parent := r.p
+	slot := slotOf(t, r)
+	for {
+		q := *slot
+		if q == nil {
+			t.size++
+			n := &Node{Val: val, p: parent, size: 1}
+			*slot = n
+			hint.len = d
+			for a := parent; a != nil; a = a.p {
+				a.size++
+			}
+			t.bubbleInsert(n, true)
+			return
+		}
+
+		c := cmp(val, q.Val)
+		if c == 0 {
+			q.Val = val
+			hint.len = d
+			return
+		}
+
+		a := (c + 1) / 2
+		hint.set(d, uint8(a))
+		parent, slot = q, &q.c[a]
+		d++
+	}
+}
+
+// bubbleInsert applies insertFix up the ancestor chain starting at
+// child, which just grew, for as long as fix reports that the parent's
+// balance factor needs updating too. It's the iterative equivalent of
+// the fix propagation that happens naturally while a normal recursive
+// insert unwinds.
+func (t *Tree) bubbleInsert(child *Node, fix bool) {
+	for fix {
+		parent := child.p
+		if parent == nil {
+			return
+		}
+		c := int8(-1)
+		if parent.c[1] == child {
+			c = 1
+		}
+		slot := slotOf(t, parent)
+		fix = insertFix(c, slot)
+		child = *slot
+	}
+}
+
+// DeleteHint behaves like Delete, but uses hint to relocate near val
+// instead of always descending from the root, and updates hint for
+// later calls.
+func (t *Tree) DeleteHint(val Ordered, hint *PathHint) {
+	if t == nil {
+		return
+	}
+
+	g, d := hint.follow(t.root)
+	if g == nil {
+		t.del(val, &t.root)
+		hint.len = 0
+		return
+	}
+
+	r, rd := g, d
+	if cmp(val, g.Val) != 0 {
+		r, rd = ascend(g, d, val)
+	}
+	t.deleteFrom(r, val, hint, rd)
+}
+
+// deleteFrom performs a normal comparison-driven descent from r, which is
+// at depth d, to delete val if present, recording directions into hint
+// from d on, then removes the matching node if one is found. It's the
+// resume-from-r counterpart of t.del, which always starts from the root.
+func (t *Tree) deleteFrom(r *Node, val Ordered, hint *PathHint, d int8) {
+	slot := slotOf(t, r)
+	for {
+		q := *slot
+		if q == nil {
+			hint.len = d
+			return
+		}
+
+		switch cmp(val, q.Val) {
+		case -1:
+			hint.set(d, 0)
+			slot = &q.c[0]
+		case 0:
+			hint.len = d
+			t.removeNode(q, slot)
+			return
+		case 1:
+			hint.set(d, 1)
+			slot = &q.c[1]
+		}
+		d++
+	}
+}
+
+// removeNode removes q, found at slot, from the tree: it splices q out
+// directly if q has at most one child, or otherwise replaces q.Val with
+// its inorder successor and removes that successor node instead, exactly
+// as t.del does. It keeps every ancestor above q's old position sized
+// correctly (q's own size, if q survives as a shell holding the successor
+// value, is recomputed here after delmin), then rebalances: at q itself if
+// its right subtree shrank, then upward from q's parent.
+func (t *Tree) removeNode(q *Node, slot **Node) {
+	t.size--
+	parent := q.p
+	c := int8(-1)
+	if parent != nil && parent.c[1] == q {
+		c = 1
+	}
+
+	fix := true
+	if q.c[1] == nil {
+		if q.c[0] != nil {
+			q.c[0].p = q.p
+		}
+		*slot = q.c[0]
+	} else {
+		fix = delmin(&q.c[1], &q.Val)
+		q.size = 1 + size(q.c[0]) + size(q.c[1])
+		if fix {
+			fix = delFix(-1, slot)
+		}
+	}
+
+	for a := parent; a != nil; a = a.p {
+		a.size--
+	}
+	t.bubbleDelete(parent, c, fix)
+}
+
+// bubbleDelete applies delFix up the ancestor chain starting at parent,
+// whose c child subtree just shrank, for as long as fix reports that the
+// next parent up needs updating too. delFix takes the negation of the
+// shrunk child's direction, the same convention del uses when it calls
+// delFix(-c, qp).
+func (t *Tree) bubbleDelete(parent *Node, c int8, fix bool) {
+	for fix && parent != nil {
+		slot := slotOf(t, parent)
+		fix = delFix(-c, slot)
+		n := *slot
+		grandparent := n.p
+		if grandparent == nil {
+			return
+		}
+		c = -1
+		if grandparent.c[1] == n {
+			c = 1
+		}
+		parent = grandparent
+	}
+}
@@ -0,0 +1,168 @@
+package avl
+
+import "testing"
+
+func checkParents(t *testing.T, n *Node) {
+	if n == nil {
+		return
+	}
+	if n.c[0] != nil && n.c[0].p != n {
+		t.Errorf("bad parent pointer on left child of %v", n.Val)
+	}
+	if n.c[1] != nil && n.c[1].p != n {
+		t.Errorf("bad parent pointer on right child of %v", n.Val)
+	}
+	checkParents(t, n.c[0])
+	checkParents(t, n.c[1])
+}
+
+func TestLookupHintMatchesLookup(t *testing.T) {
+	tree, vals := newRandIntTreeAndMap(nNodes, randMax)
+	var hint PathHint
+	for i := 0; i < randMax; i++ {
+		got, gotOk := tree.LookupHint(Int(i), &hint)
+		wantOk := vals[Int(i)]
+		if gotOk != wantOk {
+			t.Errorf("LookupHint(%d) ok=%v, want %v", i, gotOk, wantOk)
+		}
+		if wantOk && got.(Int) != Int(i) {
+			t.Errorf("LookupHint(%d) = %v", i, got)
+		}
+	}
+}
+
+func TestInsertHintBalanced(t *testing.T) {
+	tree := new(Tree)
+	var hint PathHint
+	for i := 0; i < nNodes; i++ {
+		tree.InsertHint(Int(rng.Intn(randMax)), &hint)
+	}
+	tree.checkBalance(t)
+	checkParents(t, tree.root)
+}
+
+func TestInsertHintSize(t *testing.T) {
+	tree := new(Tree)
+	var hint PathHint
+	vals := make(map[Int]bool)
+	for i := 0; i < nNodes; i++ {
+		v := Int(rng.Intn(randMax))
+		tree.InsertHint(v, &hint)
+		vals[v] = true
+	}
+	if len(vals) != tree.Size() {
+		t.Errorf("Size does not match: size %d, tree.Size() %d\n", len(vals), tree.Size())
+	}
+}
+
+func TestDeleteHintBalanced(t *testing.T) {
+	tree, vals := newRandIntTreeAndMap(nNodes, randMax)
+	var hint PathHint
+	for i := 0; i < nDels; i++ {
+		v := Int(rng.Intn(randMax))
+		tree.DeleteHint(v, &hint)
+		delete(vals, v)
+	}
+	if len(vals) != tree.Size() {
+		t.Errorf("Size does not match: size %d, tree.Size() %d\n", len(vals), tree.Size())
+	}
+	tree.checkBalance(t)
+	checkParents(t, tree.root)
+}
+
+func TestRandomOpsHint(t *testing.T) {
+	tree := new(Tree)
+	vals := make(map[Int]bool)
+	var hint PathHint
+	for i := 0; i < nNodes; i++ {
+		switch rng.Intn(3) {
+		case 0, 2:
+			v := Int(rng.Intn(randMax))
+			tree.InsertHint(v, &hint)
+			vals[v] = true
+		case 1:
+			v := Int(rng.Intn(randMax))
+			tree.DeleteHint(v, &hint)
+			delete(vals, v)
+		}
+	}
+	if len(vals) != tree.Size() {
+		t.Errorf("Size does not match: size %d, tree.Size() %d\n", len(vals), tree.Size())
+	}
+	tree.checkBalance(t)
+	checkParents(t, tree.root)
+	tree.checkOrdered(t)
+}
+
+// TestRandomOpsHintRelocates checks that InsertHint and DeleteHint actually
+// relocate near a shared hint instead of always redescending from the root,
+// and that Node.size stays correct along every path a relocated descent or
+// rebalance touches.
+func TestRandomOpsHintRelocates(t *testing.T) {
+	tree := new(Tree)
+	vals := make(map[Int]bool)
+	var hint PathHint
+	climbedToRoot, ascends := 0, 0
+	for i := 0; i < nNodes; i++ {
+		v := Int(rng.Intn(randMax))
+		g, d := hint.follow(tree.root)
+		if g != nil && cmp(v, g.Val) != 0 {
+			if _, rd := ascend(g, d, v); rd == 0 {
+				climbedToRoot++
+			}
+			ascends++
+		}
+		if rng.Intn(3) == 1 {
+			tree.DeleteHint(v, &hint)
+			delete(vals, v)
+		} else {
+			tree.InsertHint(v, &hint)
+			vals[v] = true
+		}
+	}
+	if ascends > 0 && climbedToRoot == ascends {
+		t.Errorf("ascend always climbed to the root (%d/%d); hints never relocate", climbedToRoot, ascends)
+	}
+	if len(vals) != tree.Size() {
+		t.Errorf("Size does not match: size %d, tree.Size() %d\n", len(vals), tree.Size())
+	}
+	checkSizes(t, tree.root)
+	tree.checkBalance(t)
+	checkParents(t, tree.root)
+	for k := 0; k < tree.Size(); k++ {
+		if got := tree.Select(k).Index(); got != k {
+			t.Errorf("Select(%d).Index() = %d, want %d", k, got, k)
+		}
+	}
+}
+
+func BenchmarkLookupHintSequential100(b *testing.B) {
+	benchmarkLookupHintSequential(b, 100)
+}
+
+func BenchmarkLookupHintSequential1000(b *testing.B) {
+	benchmarkLookupHintSequential(b, 1000)
+}
+
+func BenchmarkLookupHintSequential10000(b *testing.B) {
+	benchmarkLookupHintSequential(b, 10000)
+}
+
+func BenchmarkLookupHintSequential100000(b *testing.B) {
+	benchmarkLookupHintSequential(b, 100000)
+}
+
+func benchmarkLookupHintSequential(b *testing.B, size int) {
+	b.StopTimer()
+	tree := new(Tree)
+	for n := 0; n < size; n++ {
+		tree.Insert(Int(n))
+	}
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		var hint PathHint
+		for n := 0; n < size; n++ {
+			tree.LookupHint(Int(n), &hint)
+		}
+	}
+}
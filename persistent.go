@@ -0,0 +1,507 @@
+package avl
+
+// PTree holds elements of a persistent (applicative) AVL tree.
+//
+// Insert, Delete, and Update never modify the receiver; they return a new
+// *PTree that shares every subtree unaffected by the change. This makes it
+// cheap to keep previous versions of a PTree around as snapshots, or to use
+// a PTree as a copy-on-write map.
+type PTree struct {
+	root *pnode
+	size int
+}
+
+// A pnode holds an Ordered element of a PTree. Unlike Node, pnode has no
+// parent pointer: a pnode may be shared by many trees at once, so it can't
+// point back to a single parent. PTree has no iterator: Iterator works on
+// Tree's Node only.
+//
+// h and size are redundant with each other and with the subtree's shape,
+// but both are cheap to keep current and let Union, Intersection, and
+// Difference join subtrees in O(log n) without walking them to measure
+// height or count elements.
+type pnode struct {
+	Val  Ordered
+	c    [2]*pnode
+	b    int8
+	h    int8
+	size int32
+}
+
+func (n *pnode) clone() *pnode {
+	m := *n
+	return &m
+}
+
+// pht returns the height of n, or 0 for a nil subtree.
+func pht(n *pnode) int8 {
+	if n == nil {
+		return 0
+	}
+	return n.h
+}
+
+// psize returns the number of elements in n, or 0 for a nil subtree.
+func psize(n *pnode) int32 {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func maxInt8(a, b int8) int8 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// fixHeightSize recomputes n.h and n.size from its children, after n.c has
+// changed. It leaves n.b alone: pinsertFix and pdelFix, and the
+// pdoublerot case in particular, read a node's balance factor from before
+// the surrounding rotation to decide how to redistribute it afterward, so
+// nothing along that path may overwrite b from heights instead.
+func (n *pnode) fixHeightSize() {
+	n.h = 1 + maxInt8(pht(n.c[0]), pht(n.c[1]))
+	n.size = 1 + psize(n.c[0]) + psize(n.c[1])
+}
+
+// fixBalance sets n.b from the heights of its children, which must already
+// be current. It's only used by the join/split machinery behind Union,
+// Intersection, and Difference (pjoin's balanced case and pjoinHeavy's
+// rebalancing), which computes balance factors fresh from heights rather
+// than carrying them forward incrementally the way pinsertFix/pdelFix do.
+func (n *pnode) fixBalance() {
+	n.b = pht(n.c[1]) - pht(n.c[0])
+}
+
+// Size returns the number of elements stored in the tree.
+func (t *PTree) Size() int {
+	if t == nil {
+		return 0
+	}
+	return t.size
+}
+
+// Lookup looks up val and returns the matching element if it is found.
+//
+// Val's Less implementation must be able to handle comparisons to elements
+// stored in this tree.
+func (t *PTree) Lookup(val Ordered) (match Ordered, ok bool) {
+	if t == nil {
+		return
+	}
+	n := t.root
+	for n != nil {
+		switch cmp(val, n.Val) {
+		case -1:
+			n = n.c[0]
+		case 0:
+			return n.Val, true
+		case 1:
+			n = n.c[1]
+		}
+	}
+	return
+}
+
+// Insert returns a new *PTree with val inserted, sharing every subtree of t
+// that the insertion doesn't touch. t is left unmodified.
+//
+// Val's Less implementation must be able to handle comparisons to elements
+// stored in this tree.
+func (t *PTree) Insert(val Ordered) *PTree {
+	var old *pnode
+	if t != nil {
+		old = t.root
+	}
+	root, _, replaced := pinsert(old, val)
+	size := t.Size()
+	if !replaced {
+		size++
+	}
+	return &PTree{root: root, size: size}
+}
+
+// Update replaces val in the tree if a matching element is already
+// present, returning a new *PTree that shares every unchanged subtree of
+// t. If no match exists, t is returned unchanged.
+func (t *PTree) Update(val Ordered) *PTree {
+	if _, ok := t.Lookup(val); !ok {
+		return t
+	}
+	return t.Insert(val)
+}
+
+// Delete returns a new *PTree with the element matching val removed,
+// sharing every subtree of t that the deletion doesn't touch. If no match
+// exists, t is returned unchanged.
+//
+// Val's Less implementation must be able to handle comparisons to elements
+// stored in this tree.
+func (t *PTree) Delete(val Ordered) *PTree {
+	if t == nil {
+		return t
+	}
+	root, _, found := pdel(t.root, val)
+	if !found {
+		return t
+	}
+	return &PTree{root: root, size: t.size - 1}
+}
+
+// pinsert inserts val into the subtree rooted at q, returning the new
+// subtree root, whether the subtree grew taller, and whether val replaced
+// an existing element.
+func pinsert(q *pnode, val Ordered) (_ *pnode, grew, replaced bool) {
+	if q == nil {
+		return &pnode{Val: val, h: 1, size: 1}, true, false
+	}
+
+	c := cmp(val, q.Val)
+	if c == 0 {
+		q = q.clone()
+		q.Val = val
+		return q, false, true
+	}
+
+	q = q.clone()
+	a := (c + 1) / 2
+	child, fix, replaced := pinsert(q.c[a], val)
+	q.c[a] = child
+	q.fixHeightSize()
+	if !fix {
+		return q, false, replaced
+	}
+	q, grew = pinsertFix(c, q)
+	return q, grew, replaced
+}
+
+// pdel deletes val from the subtree rooted at q, returning the new subtree
+// root, whether the subtree shrank, and whether a match was found.
+func pdel(q *pnode, val Ordered) (_ *pnode, shrank, found bool) {
+	if q == nil {
+		return nil, false, false
+	}
+
+	c := cmp(val, q.Val)
+	if c == 0 {
+		if q.c[1] == nil {
+			return q.c[0], true, true
+		}
+		q = q.clone()
+		child, min, fix := pdelmin(q.c[1])
+		q.c[1] = child
+		q.Val = min
+		q.fixHeightSize()
+		if fix {
+			q, fix = pdelFix(-1, q)
+		}
+		return q, fix, true
+	}
+
+	a := (c + 1) / 2
+	child, fix, found := pdel(q.c[a], val)
+	if !found {
+		return q, false, false
+	}
+	q = q.clone()
+	q.c[a] = child
+	q.fixHeightSize()
+	if fix {
+		q, fix = pdelFix(-c, q)
+	}
+	return q, fix, true
+}
+
+func pdelmin(q *pnode) (_ *pnode, min Ordered, shrank bool) {
+	if q.c[0] == nil {
+		return q.c[1], q.Val, true
+	}
+	q = q.clone()
+	child, min, fix := pdelmin(q.c[0])
+	q.c[0] = child
+	q.fixHeightSize()
+	if fix {
+		q, fix = pdelFix(1, q)
+	}
+	return q, min, fix
+}
+
+func pinsertFix(c int8, s *pnode) (*pnode, bool) {
+	if s.b == 0 {
+		s.b = c
+		return s, true
+	}
+
+	if s.b == -c {
+		s.b = 0
+		return s, false
+	}
+
+	if s.c[(c+1)/2].b == c {
+		s = psinglerot(c, s)
+	} else {
+		s = pdoublerot(c, s)
+	}
+	return s, false
+}
+
+func pdelFix(c int8, s *pnode) (*pnode, bool) {
+	if s.b == 0 {
+		s.b = c
+		return s, false
+	}
+
+	if s.b == -c {
+		s.b = 0
+		return s, true
+	}
+
+	a := (c + 1) / 2
+	if s.c[a].b == 0 {
+		s = protate(c, s)
+		s.b = -c
+		return s, false
+	}
+
+	if s.c[a].b == c {
+		s = psinglerot(c, s)
+	} else {
+		s = pdoublerot(c, s)
+	}
+	return s, true
+}
+
+func psinglerot(c int8, s *pnode) *pnode {
+	s.b = 0
+	s = protate(c, s)
+	s.b = 0
+	return s
+}
+
+func pdoublerot(c int8, s *pnode) *pnode {
+	a := (c + 1) / 2
+	r := s.c[a].clone()
+	s.c[a] = protate(-c, r)
+	p := protate(c, s)
+
+	switch {
+	default:
+		s.b = 0
+		r.b = 0
+	case p.b == c:
+		s.b = -c
+		r.b = 0
+	case p.b == -c:
+		s.b = 0
+		r.b = c
+	}
+
+	p.b = 0
+	return p
+}
+
+// protate rotates s in the direction given by c, the same rotation rotate
+// performs for the mutable Tree, except that it clones the node it moves
+// into s's place instead of mutating it in place. s itself must already be
+// a node private to the caller.
+func protate(c int8, s *pnode) *pnode {
+	a := (c + 1) / 2
+	r := s.c[a].clone()
+	s.c[a] = r.c[a^1]
+	r.c[a^1] = s
+	s.fixHeightSize()
+	r.fixHeightSize()
+	return r
+}
+
+// proot returns t's root, or nil for a nil *PTree.
+func (t *PTree) proot() *pnode {
+	if t == nil {
+		return nil
+	}
+	return t.root
+}
+
+// Union returns a new *PTree containing every element of t and other. If
+// both trees contain an element that compares equal, the element from t is
+// kept. The result is built by the standard divide-and-conquer merge,
+// splitting other around t's root and recursing on each side, so it shares
+// every subtree of t and other that the merge doesn't touch, rather than
+// rebuilding either tree from scratch.
+func (t *PTree) Union(other *PTree) *PTree {
+	root := punion(t.proot(), other.proot())
+	return &PTree{root: root, size: int(psize(root))}
+}
+
+// Intersection returns a new *PTree containing only the elements present
+// in both t and other, built with the same split-and-recurse merge as
+// Union.
+func (t *PTree) Intersection(other *PTree) *PTree {
+	root := pintersection(t.proot(), other.proot())
+	return &PTree{root: root, size: int(psize(root))}
+}
+
+// Difference returns a new *PTree containing the elements of t that are
+// not present in other, built with the same split-and-recurse merge as
+// Union.
+func (t *PTree) Difference(other *PTree) *PTree {
+	root := pdifference(t.proot(), other.proot())
+	return &PTree{root: root, size: int(psize(root))}
+}
+
+// punion returns the union of p and q, keeping p's element on a match. It
+// splits q around p's root and recurses on the two sides in parallel,
+// joining the results back together under p's root value.
+func punion(p, q *pnode) *pnode {
+	if p == nil {
+		return q
+	}
+	if q == nil {
+		return p
+	}
+	lo, _, hi := psplit(q, p.Val)
+	return pjoin(punion(p.c[0], lo), p.Val, punion(p.c[1], hi))
+}
+
+// pintersection returns the elements common to p and q. It splits q around
+// p's root and recurses on the two sides, keeping p's root value only if
+// it was also found in q.
+func pintersection(p, q *pnode) *pnode {
+	if p == nil || q == nil {
+		return nil
+	}
+	lo, found, hi := psplit(q, p.Val)
+	left, right := pintersection(p.c[0], lo), pintersection(p.c[1], hi)
+	if found {
+		return pjoin(left, p.Val, right)
+	}
+	return pjoin2(left, right)
+}
+
+// pdifference returns the elements of p not present in q. It splits q
+// around p's root and recurses on the two sides, dropping p's root value
+// if it was found in q.
+func pdifference(p, q *pnode) *pnode {
+	if p == nil {
+		return nil
+	}
+	if q == nil {
+		return p
+	}
+	lo, found, hi := psplit(q, p.Val)
+	left, right := pdifference(p.c[0], lo), pdifference(p.c[1], hi)
+	if found {
+		return pjoin2(left, right)
+	}
+	return pjoin(left, p.Val, right)
+}
+
+// psplit splits n into the elements < val, whether an element equal to val
+// is present, and the elements > val, reusing every subtree that falls
+// entirely on one side of val.
+func psplit(n *pnode, val Ordered) (lo *pnode, found bool, hi *pnode) {
+	if n == nil {
+		return nil, false, nil
+	}
+	switch cmp(val, n.Val) {
+	case 0:
+		return n.c[0], true, n.c[1]
+	case -1:
+		lo, found, hi := psplit(n.c[0], val)
+		return lo, found, pjoin(hi, n.Val, n.c[1])
+	default:
+		lo, found, hi := psplit(n.c[1], val)
+		return pjoin(n.c[0], n.Val, lo), found, hi
+	}
+}
+
+// pjoin returns a tree holding every element of left, mid, and right, where
+// every element of left is less than mid and every element of right is
+// greater than mid. left and right may differ in height by any amount;
+// pjoin descends the taller side until the heights are within one of each
+// other, attaches mid there, and rebalances back up, reusing every subtree
+// it doesn't descend into.
+func pjoin(left *pnode, mid Ordered, right *pnode) *pnode {
+	switch {
+	case pht(left) > pht(right)+1:
+		return pjoinHeavy(1, left, mid, right)
+	case pht(right) > pht(left)+1:
+		return pjoinHeavy(-1, right, mid, left)
+	}
+	n := &pnode{Val: mid, c: [2]*pnode{left, right}}
+	n.fixHeightSize()
+	n.fixBalance()
+	return n
+}
+
+// pjoinHeavy joins heavy, mid, and light, where heavy is the taller of the
+// two subtrees being joined by pjoin and c says which of heavy's children
+// faces mid and light (1 for heavy's right, -1 for heavy's left; light sits
+// on the opposite side of mid from that child). It descends that side of
+// heavy until it finds a child no more than one taller than light, joins mid and
+// light in as that child's sibling, then rebalances heavy's spine back up
+// using the same single/double rotations as an ordinary insert.
+func pjoinHeavy(c int8, heavy *pnode, mid Ordered, light *pnode) *pnode {
+	heavy = heavy.clone()
+	a := (c + 1) / 2
+	if pht(heavy.c[a]) <= pht(light)+1 {
+		if c == 1 {
+			heavy.c[a] = pjoin(heavy.c[a], mid, light)
+		} else {
+			heavy.c[a] = pjoin(light, mid, heavy.c[a])
+		}
+	} else {
+		heavy.c[a] = pjoinHeavy(c, heavy.c[a], mid, light)
+	}
+	heavy.fixHeightSize()
+
+	switch bf := pht(heavy.c[1]) - pht(heavy.c[0]); {
+	case bf > 1:
+		if pht(heavy.c[1].c[0]) > pht(heavy.c[1].c[1]) {
+			inner := heavy.c[1].clone()
+			heavy.c[1] = protate(-1, inner)
+			inner.fixBalance()
+			heavy.c[1].fixBalance()
+		}
+		heavy = protate(1, heavy)
+		heavy.c[0].fixBalance()
+		heavy.fixBalance()
+	case bf < -1:
+		if pht(heavy.c[0].c[1]) > pht(heavy.c[0].c[0]) {
+			inner := heavy.c[0].clone()
+			heavy.c[0] = protate(1, inner)
+			inner.fixBalance()
+			heavy.c[0].fixBalance()
+		}
+		heavy = protate(-1, heavy)
+		heavy.c[1].fixBalance()
+		heavy.fixBalance()
+	default:
+		heavy.b = bf
+	}
+	return heavy
+}
+
+// pjoin2 joins left and right, where every element of left is less than
+// every element of right, with no explicit element of its own: it lifts
+// left's maximum (or, if left is empty, just returns right) to serve as
+// the join point.
+func pjoin2(left, right *pnode) *pnode {
+	if left == nil {
+		return right
+	}
+	rest, max := psplitMax(left)
+	return pjoin(rest, max, right)
+}
+
+// psplitMax removes and returns n's maximum element, along with the tree
+// that remains once it's gone.
+func psplitMax(n *pnode) (rest *pnode, max Ordered) {
+	if n.c[1] == nil {
+		return n.c[0], n.Val
+	}
+	rest, max = psplitMax(n.c[1])
+	return pjoin(n.c[0], n.Val, rest), max
+}
@@ -0,0 +1,227 @@
+package avl
+
+import (
+	"sort"
+	"testing"
+)
+
+func pcheckBalance(t *testing.T, n *pnode) int8 {
+	if n == nil {
+		return 0
+	}
+	lh := pcheckBalance(t, n.c[0])
+	rh := pcheckBalance(t, n.c[1])
+	if n.h != 1+maxInt8(lh, rh) {
+		t.Errorf("node %v: h = %d, want %d", n.Val, n.h, 1+maxInt8(lh, rh))
+	}
+	if n.b != rh-lh {
+		t.Errorf("node %v: b = %d, want %d", n.Val, n.b, rh-lh)
+	}
+	if n.b < -1 || n.b > 1 {
+		t.Errorf("node %v: unbalanced, b = %d", n.Val, n.b)
+	}
+	return n.h
+}
+
+func pcheckSize(t *testing.T, n *pnode) int32 {
+	if n == nil {
+		return 0
+	}
+	want := 1 + pcheckSize(t, n.c[0]) + pcheckSize(t, n.c[1])
+	if n.size != want {
+		t.Errorf("node %v: size = %d, want %d", n.Val, n.size, want)
+	}
+	return n.size
+}
+
+func pcheckOrdered(t *testing.T, tree *PTree) {
+	var prev Int
+	first := true
+	tree.root.inorder(func(v Ordered) {
+		cur := v.(Int)
+		if !first && cur <= prev {
+			t.Errorf("out of order: %v before/at %v", prev, cur)
+		}
+		prev, first = cur, false
+	})
+}
+
+func (n *pnode) inorder(f func(Ordered)) {
+	if n == nil {
+		return
+	}
+	n.c[0].inorder(f)
+	f(n.Val)
+	n.c[1].inorder(f)
+}
+
+func pvals(tree *PTree) []int {
+	var got []int
+	tree.root.inorder(func(v Ordered) { got = append(got, int(v.(Int))) })
+	return got
+}
+
+func newRandPTree(n, randMax int) (*PTree, map[Int]bool) {
+	tree := new(PTree)
+	vals := make(map[Int]bool)
+	for i := 0; i < n; i++ {
+		v := Int(rng.Intn(randMax))
+		tree = tree.Insert(v)
+		vals[v] = true
+	}
+	return tree, vals
+}
+
+func TestPTreeInsertBalance(t *testing.T) {
+	tree, vals := newRandPTree(nNodes, randMax)
+	if tree.Size() != len(vals) {
+		t.Fatalf("Size() = %d, want %d", tree.Size(), len(vals))
+	}
+	pcheckBalance(t, tree.root)
+	pcheckSize(t, tree.root)
+	pcheckOrdered(t, tree)
+}
+
+func TestPTreeDelete(t *testing.T) {
+	tree, vals := newRandPTree(nNodes, randMax)
+	for i := 0; i < nDels; i++ {
+		v := Int(rng.Intn(randMax))
+		tree = tree.Delete(v)
+		delete(vals, v)
+	}
+	if tree.Size() != len(vals) {
+		t.Fatalf("Size() = %d, want %d", tree.Size(), len(vals))
+	}
+	pcheckBalance(t, tree.root)
+	pcheckSize(t, tree.root)
+	pcheckOrdered(t, tree)
+	for v := range vals {
+		if _, ok := tree.Lookup(v); !ok {
+			t.Errorf("Lookup(%v) = false, want true", v)
+		}
+	}
+}
+
+// TestPTreeSnapshotImmutable checks that every snapshot returned along a
+// sequence of Inserts and Deletes keeps showing exactly the contents it had
+// when it was taken, even after later snapshots mutate the same tree.
+func TestPTreeSnapshotImmutable(t *testing.T) {
+	tree := new(PTree)
+	var snaps []*PTree
+	var snapVals []map[Int]bool
+	cur := make(map[Int]bool)
+	for i := 0; i < 500; i++ {
+		v := Int(rng.Intn(200))
+		if rng.Intn(3) == 1 {
+			tree = tree.Delete(v)
+			delete(cur, v)
+		} else {
+			tree = tree.Insert(v)
+			cur[v] = true
+		}
+		snap := make(map[Int]bool, len(cur))
+		for k := range cur {
+			snap[k] = true
+		}
+		snaps = append(snaps, tree)
+		snapVals = append(snapVals, snap)
+	}
+	for i, snap := range snaps {
+		want := snapVals[i]
+		if snap.Size() != len(want) {
+			t.Fatalf("snapshot %d: Size() = %d, want %d", i, snap.Size(), len(want))
+		}
+		for v := range want {
+			if _, ok := snap.Lookup(v); !ok {
+				t.Fatalf("snapshot %d: Lookup(%v) = false, want true", i, v)
+			}
+		}
+	}
+}
+
+func sortedIntSlice(vals map[Int]bool) []int {
+	var s []int
+	for v := range vals {
+		s = append(s, int(v))
+	}
+	sort.Ints(s)
+	return s
+}
+
+func TestPTreeUnion(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		t1, v1 := newRandPTree(nNodes/4, randMax)
+		t2, v2 := newRandPTree(nNodes/4, randMax)
+		u := t1.Union(t2)
+		want := make(map[Int]bool)
+		for v := range v1 {
+			want[v] = true
+		}
+		for v := range v2 {
+			want[v] = true
+		}
+		if u.Size() != len(want) {
+			t.Fatalf("Union Size() = %d, want %d", u.Size(), len(want))
+		}
+		if !equalInts(pvals(u), sortedIntSlice(want)) {
+			t.Fatalf("Union contents mismatch")
+		}
+		pcheckBalance(t, u.root)
+		pcheckSize(t, u.root)
+	}
+}
+
+func TestPTreeIntersection(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		t1, v1 := newRandPTree(nNodes/4, randMax)
+		t2, v2 := newRandPTree(nNodes/4, randMax)
+		x := t1.Intersection(t2)
+		want := make(map[Int]bool)
+		for v := range v1 {
+			if v2[v] {
+				want[v] = true
+			}
+		}
+		if x.Size() != len(want) {
+			t.Fatalf("Intersection Size() = %d, want %d", x.Size(), len(want))
+		}
+		if !equalInts(pvals(x), sortedIntSlice(want)) {
+			t.Fatalf("Intersection contents mismatch")
+		}
+		pcheckBalance(t, x.root)
+		pcheckSize(t, x.root)
+	}
+}
+
+func TestPTreeDifference(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		t1, v1 := newRandPTree(nNodes/4, randMax)
+		t2, v2 := newRandPTree(nNodes/4, randMax)
+		d := t1.Difference(t2)
+		want := make(map[Int]bool)
+		for v := range v1 {
+			if !v2[v] {
+				want[v] = true
+			}
+		}
+		if d.Size() != len(want) {
+			t.Fatalf("Difference Size() = %d, want %d", d.Size(), len(want))
+		}
+		if !equalInts(pvals(d), sortedIntSlice(want)) {
+			t.Fatalf("Difference contents mismatch")
+		}
+		pcheckBalance(t, d.root)
+		pcheckSize(t, d.root)
+	}
+}
+
+// TestPTreeSetOpsShareSubtrees checks that a set operation against an empty
+// tree hands back the other tree's own root rather than rebuilding it,
+// confirming subtrees are reused instead of being walked node by node.
+func TestPTreeSetOpsShareSubtrees(t *testing.T) {
+	big, _ := newRandPTree(nNodes, randMax)
+	empty := new(PTree)
+	if u := big.Union(empty); u.root != big.root {
+		t.Errorf("Union with empty tree should return t's own root unchanged")
+	}
+}